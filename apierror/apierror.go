@@ -0,0 +1,42 @@
+// Package apierror defines typed API errors so handlers can report failures
+// uniformly via c.Error(...) instead of writing the JSON response themselves.
+package apierror
+
+import "net/http"
+
+// APIError is a handler-level error carrying enough information for the
+// error-handling middleware to render a consistent JSON response.
+type APIError struct {
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func NotFound(message string) *APIError {
+	return &APIError{Status: http.StatusNotFound, Code: "not_found", Message: message}
+}
+
+func Validation(message string, details interface{}) *APIError {
+	return &APIError{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Message: message, Details: details}
+}
+
+func BadRequest(message string) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: "bad_request", Message: message}
+}
+
+func Unauthorized(message string) *APIError {
+	return &APIError{Status: http.StatusUnauthorized, Code: "unauthorized", Message: message}
+}
+
+func Conflict(message string) *APIError {
+	return &APIError{Status: http.StatusConflict, Code: "conflict", Message: message}
+}
+
+func Internal(err error) *APIError {
+	return &APIError{Status: http.StatusInternalServerError, Code: "internal_error", Message: err.Error()}
+}
@@ -7,26 +7,70 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/AbbasRizvi3/GoLangAssignment2/apierror"
+	"github.com/AbbasRizvi3/GoLangAssignment2/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 const (
 	databaseName   = "taskdb"
 	collectionName = "tasks"
-	port           = ":8000"
+
+	defaultListenAddr      = ":8000"
+	defaultShutdownTimeout = 10 * time.Second
 )
 
+func listenAddr() string {
+	if addr := os.Getenv("LISTEN_ADDR"); addr != "" {
+		return addr
+	}
+	return defaultListenAddr
+}
+
+func shutdownTimeout() time.Duration {
+	raw := os.Getenv("SHUTDOWN_TIMEOUT_SECONDS")
+	if raw == "" {
+		return defaultShutdownTimeout
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(n) * time.Second
+}
+
 type Task struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Title     string             `json:"title" bson:"title"`
+	Body      string             `json:"body" bson:"body,omitempty"`
 	Completed bool               `json:"completed" bson:"completed,omitempty"`
+	OwnerID   primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ownerID pulls the authenticated user's id, set by middleware.Authorize,
+// off the Gin context and parses it into a Mongo ObjectID.
+func ownerID(c *gin.Context) (primitive.ObjectID, error) {
+	userID := c.MustGet("userId").(string)
+	return primitive.ObjectIDFromHex(userID)
+}
+
+// ownedTaskFilter scopes a single-task lookup to the given owner, so a
+// request for someone else's task id simply doesn't match.
+func ownedTaskFilter(id, owner primitive.ObjectID) bson.M {
+	return bson.M{"_id": id, "ownerId": owner}
 }
 
 var router *gin.Engine
@@ -50,44 +94,74 @@ func SetUpDatabase() (*mongo.Client, error) {
 		return nil, err
 	}
 
+	usersCollection := client.Database(databaseName).Collection(usersCollectionName)
+	_, err = usersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"email": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	log.Println("Connected to MongoDB!")
 	Client = client
 	return client, nil
 }
 
-func throwError(status int, err error, c *gin.Context) {
-	code := status
-	if code == 0 {
-		code = http.StatusInternalServerError
+// Healthz is a liveness probe: it reports the process is up, regardless of
+// whether its dependencies (Mongo) are reachable.
+func Healthz(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "ok"})
+}
+
+// Readyz is a readiness probe: it additionally checks that Mongo is
+// reachable, since a process that's up but can't serve requests shouldn't
+// receive traffic.
+func Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := Client.Ping(ctx, readpref.Primary()); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "mongo_unreachable"})
+		return
 	}
-	c.JSON(code, gin.H{
-		"error": err.Error(),
-	})
+
+	c.JSON(200, gin.H{"status": "ok"})
 }
 
 func CreateTask(c *gin.Context) {
 	var task Task
 	err := c.BindJSON(&task)
 	if err != nil {
-		throwError(http.StatusBadRequest, fmt.Errorf("invalid json"), c)
+		c.Error(apierror.BadRequest("invalid json"))
 		return
 	}
 
 	title := task.Title
 	if title == "" {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Title cannot be empty"})
+		c.Error(apierror.Validation("Title cannot be empty", nil))
 		return
 	}
 	if len(title) < 5 {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Title length must be at least 5"})
+		c.Error(apierror.Validation("Title length must be at least 5", nil))
 		return
 	}
 	task.Completed = false
 
+	owner, err := ownerID(c)
+	if err != nil {
+		c.Error(apierror.Unauthorized(err.Error()))
+		return
+	}
+	task.OwnerID = owner
+	now := time.Now()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+
 	_, err = Client.Database(databaseName).Collection(collectionName).InsertOne(context.Background(), task)
 
 	if err != nil {
-		throwError(http.StatusInternalServerError, err, c)
+		c.Error(apierror.Internal(err))
 		return
 	}
 
@@ -98,21 +172,103 @@ func CreateTask(c *gin.Context) {
 
 }
 
+const (
+	defaultTaskLimit = 20
+	maxTaskLimit     = 1000
+)
+
+// taskSortColumns are the fields GetTasks is willing to sort by; anything
+// else is rejected rather than silently passed through to Mongo.
+var taskSortColumns = map[string]bool{
+	"title":     true,
+	"completed": true,
+}
+
 func GetTasks(c *gin.Context) {
+	owner, err := ownerID(c)
+	if err != nil {
+		c.Error(apierror.Unauthorized(err.Error()))
+		return
+	}
+
+	limit := defaultTaskLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.Error(apierror.Validation("limit must be a positive integer", nil))
+			return
+		}
+		if limit > maxTaskLimit {
+			limit = maxTaskLimit
+		}
+	}
+
+	offset := 0
+	if raw := c.Query("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			c.Error(apierror.Validation("offset must be a non-negative integer", nil))
+			return
+		}
+	} else if raw := c.Query("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page <= 0 {
+			c.Error(apierror.Validation("page must be a positive integer", nil))
+			return
+		}
+		offset = (page - 1) * limit
+	}
+
+	filter := bson.M{"ownerId": owner}
+	if raw := c.Query("completed"); raw != "" {
+		completed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.Error(apierror.Validation("completed must be true or false", nil))
+			return
+		}
+		filter["completed"] = completed
+	}
+
+	sortColumn := c.DefaultQuery("sort_column", "title")
+	if !taskSortColumns[sortColumn] {
+		c.Error(apierror.Validation(fmt.Sprintf("unknown sort_column %q", sortColumn), nil))
+		return
+	}
+	sortOrder := 1
+	if strings.EqualFold(c.DefaultQuery("sort_order", "asc"), "desc") {
+		sortOrder = -1
+	}
+
+	findOptions := options.Find().
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)).
+		SetSort(bson.D{{Key: sortColumn, Value: sortOrder}})
+
+	collection := Client.Database(databaseName).Collection(collectionName)
+
+	total, err := collection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
 	var tasks []bson.M
-	cursor, err := Client.Database(databaseName).Collection(collectionName).Find(context.Background(), bson.D{})
+	cursor, err := collection.Find(context.Background(), filter, findOptions)
 	if err != nil {
-		throwError(http.StatusInternalServerError, err, c)
+		c.Error(apierror.Internal(err))
 		return
 	}
 	err = cursor.All(context.Background(), &tasks)
 	if err != nil {
-		throwError(http.StatusInternalServerError, err, c)
+		c.Error(apierror.Internal(err))
 		return
 	}
 
 	c.JSON(200, gin.H{
-		"tasks": tasks,
+		"tasks":  tasks,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
 	})
 
 }
@@ -121,13 +277,23 @@ func GetSpecificTask(c *gin.Context) {
 	id := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		throwError(http.StatusBadRequest, err, c)
+		c.Error(apierror.BadRequest(err.Error()))
+		return
+	}
+	owner, err := ownerID(c)
+	if err != nil {
+		c.Error(apierror.Unauthorized(err.Error()))
 		return
 	}
+
 	var task Task
-	err = Client.Database(databaseName).Collection(collectionName).FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&task)
+	err = Client.Database(databaseName).Collection(collectionName).FindOne(context.Background(), ownedTaskFilter(objectID, owner)).Decode(&task)
 	if err != nil {
-		throwError(http.StatusInternalServerError, err, c)
+		if err == mongo.ErrNoDocuments {
+			c.Error(apierror.NotFound("Task not found"))
+			return
+		}
+		c.Error(apierror.Internal(err))
 		return
 	}
 
@@ -136,38 +302,65 @@ func GetSpecificTask(c *gin.Context) {
 	})
 }
 
+// taskPatch mirrors Task but with pointer fields so we can tell an absent
+// field (client didn't send it) apart from its zero value.
+type taskPatch struct {
+	Title     *string `json:"title"`
+	Body      *string `json:"body"`
+	Completed *bool   `json:"completed"`
+}
+
 func UpdateTask(c *gin.Context) {
 	id := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		throwError(http.StatusBadRequest, err, c)
+		c.Error(apierror.BadRequest(err.Error()))
 		return
 	}
 
-	var task Task
-	err = c.BindJSON(&task)
+	var patch taskPatch
+	err = c.BindJSON(&patch)
 	if err != nil {
-		throwError(http.StatusInternalServerError, err, c)
+		c.Error(apierror.BadRequest("invalid json"))
 		return
 	}
 
-	if task.Title == "" {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Title cannot be empty"})
-		return
+	if patch.Title != nil {
+		if *patch.Title == "" {
+			c.Error(apierror.Validation("Title cannot be empty", nil))
+			return
+		}
+		if len(*patch.Title) < 5 {
+			c.Error(apierror.Validation("Title length must be at least 5", nil))
+			return
+		}
 	}
-	if len(task.Title) < 5 {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Title length must be at least 5"})
+
+	owner, err := ownerID(c)
+	if err != nil {
+		c.Error(apierror.Unauthorized(err.Error()))
 		return
 	}
 
-	res, err := Client.Database(databaseName).Collection(collectionName).UpdateOne(context.Background(), bson.M{"_id": objectID}, bson.M{"$set": task})
+	set := bson.M{"updatedAt": time.Now()}
+	if patch.Title != nil {
+		set["title"] = *patch.Title
+	}
+	if patch.Body != nil {
+		set["body"] = *patch.Body
+	}
+	if patch.Completed != nil {
+		set["completed"] = *patch.Completed
+	}
+
+	res, err := Client.Database(databaseName).Collection(collectionName).UpdateOne(context.Background(), ownedTaskFilter(objectID, owner), bson.M{"$set": set})
 	if err != nil {
-		throwError(http.StatusInternalServerError, err, c)
+		c.Error(apierror.Internal(err))
 		return
 	}
 
 	if res.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Task not found"})
+		c.Error(apierror.NotFound("Task not found"))
 		return
 	}
 
@@ -176,22 +369,69 @@ func UpdateTask(c *gin.Context) {
 	})
 }
 
+// CompleteTask sets a task's completed flag to the "completed" value in the
+// request body (false when the field is absent) without requiring its title.
+func CompleteTask(c *gin.Context) {
+	id := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.Error(apierror.BadRequest(err.Error()))
+		return
+	}
+
+	var body struct {
+		Completed bool `json:"completed"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.Error(apierror.BadRequest("invalid json"))
+		return
+	}
+
+	owner, err := ownerID(c)
+	if err != nil {
+		c.Error(apierror.Unauthorized(err.Error()))
+		return
+	}
+
+	set := bson.M{"completed": body.Completed, "updatedAt": time.Now()}
+	res, err := Client.Database(databaseName).Collection(collectionName).UpdateOne(context.Background(), ownedTaskFilter(objectID, owner), bson.M{"$set": set})
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	if res.MatchedCount == 0 {
+		c.Error(apierror.NotFound("Task not found"))
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Task completion updated",
+	})
+}
+
 func DeleteTask(c *gin.Context) {
 	id := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		throwError(http.StatusBadRequest, err, c)
+		c.Error(apierror.BadRequest(err.Error()))
 		return
 	}
 
-	res, err := Client.Database(databaseName).Collection(collectionName).DeleteOne(context.Background(), bson.M{"_id": objectID})
+	owner, err := ownerID(c)
 	if err != nil {
-		throwError(http.StatusInternalServerError, err, c)
+		c.Error(apierror.Unauthorized(err.Error()))
+		return
+	}
+
+	res, err := Client.Database(databaseName).Collection(collectionName).DeleteOne(context.Background(), ownedTaskFilter(objectID, owner))
+	if err != nil {
+		c.Error(apierror.Internal(err))
 		return
 	}
 
 	if res.DeletedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Task not found"})
+		c.Error(apierror.NotFound("Task not found"))
 		return
 	}
 
@@ -201,14 +441,26 @@ func DeleteTask(c *gin.Context) {
 }
 
 func SetupRoutes() {
+	router.Use(middleware.ErrorHandler())
+	router.Use(middleware.CORS(middleware.CORSConfigFromEnv()))
+
 	router.GET("/", func(ctx *gin.Context) {
 		ctx.String(200, "hello")
 	})
-	router.POST("/tasks", CreateTask)
-	router.GET("/tasks", GetTasks)
-	router.GET("/tasks/:id", GetSpecificTask)
-	router.PUT("/tasks/:id", UpdateTask)
-	router.DELETE("/tasks/:id", DeleteTask)
+	router.GET("/healthz", Healthz)
+	router.GET("/readyz", Readyz)
+
+	router.POST("/users/register", RegisterUser)
+	router.POST("/users/login", LoginUser)
+
+	tasks := router.Group("/tasks")
+	tasks.Use(middleware.Authorize())
+	tasks.POST("", CreateTask)
+	tasks.GET("", GetTasks)
+	tasks.GET("/:id", GetSpecificTask)
+	tasks.PUT("/:id", UpdateTask)
+	tasks.PATCH("/:id/complete", CompleteTask)
+	tasks.DELETE("/:id", DeleteTask)
 }
 
 func init() {
@@ -226,22 +478,35 @@ func main() {
 	}
 	SetupRoutes()
 
+	srv := &http.Server{
+		Addr:    listenAddr(),
+		Handler: router,
+	}
+
 	go func() {
-		if err := router.Run(port); err != nil {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Printf("server stopped: %v\n", err)
 		}
 	}()
 
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
+	log.Println("shutting down")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("server shutdown error:", err)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 	if Client != nil {
-		if err := Client.Disconnect(ctx); err != nil {
+		disconnectCtx, disconnectCancel := context.WithTimeout(context.Background(), shutdownTimeout())
+		defer disconnectCancel()
+		if err := Client.Disconnect(disconnectCtx); err != nil {
 			log.Println("mongo disconnect error:", err)
 		}
 	}
-	log.Println("shutting down")
+	log.Println("shutdown complete")
 }
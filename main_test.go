@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOwnedTaskFilterScopesByOwner(t *testing.T) {
+	taskID := primitive.NewObjectID()
+	owner := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	filter := ownedTaskFilter(taskID, owner)
+
+	if filter["_id"] != taskID {
+		t.Errorf("filter[_id] = %v, want %v", filter["_id"], taskID)
+	}
+	if filter["ownerId"] != owner {
+		t.Errorf("filter[ownerId] = %v, want %v", filter["ownerId"], owner)
+	}
+	if filter["ownerId"] == other {
+		t.Errorf("filter[ownerId] must not match an unrelated owner")
+	}
+}
+
+func TestOwnerIDReadsAuthenticatedUser(t *testing.T) {
+	userID := primitive.NewObjectID()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("userId", userID.Hex())
+
+	got, err := ownerID(c)
+	if err != nil {
+		t.Fatalf("ownerID returned error: %v", err)
+	}
+	if got != userID {
+		t.Errorf("ownerID = %v, want %v", got, userID)
+	}
+}
+
+func TestOwnerIDRejectsMalformedUserID(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("userId", "not-an-object-id")
+
+	if _, err := ownerID(c); err == nil {
+		t.Error("ownerID should reject a malformed userId claim, got nil error")
+	}
+}
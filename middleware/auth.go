@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"os"
+	"strings"
+
+	"github.com/AbbasRizvi3/GoLangAssignment2/apierror"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTSecret is the HMAC signing key used for both issuing tokens (user.go)
+// and verifying them (Authorize). It lives here, not duplicated per package,
+// so the two can never drift out of sync.
+func JWTSecret() []byte {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "dev-secret-change-me"
+	}
+	return []byte(secret)
+}
+
+// Authorize extracts the bearer token from the Authorization header, verifies
+// it, and injects the userId claim into the Gin context for downstream handlers.
+func Authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Error(apierror.Unauthorized("Authorization header required"))
+			c.Abort()
+			return
+		}
+
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.Error(apierror.Unauthorized("Authorization header must be a Bearer token"))
+			c.Abort()
+			return
+		}
+
+		token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, jwt.ErrSignatureInvalid
+			}
+			return JWTSecret(), nil
+		})
+		if err != nil || !token.Valid {
+			c.Error(apierror.Unauthorized("Invalid or expired token"))
+			c.Abort()
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			c.Error(apierror.Unauthorized("Invalid token claims"))
+			c.Abort()
+			return
+		}
+
+		userID, ok := claims["userId"].(string)
+		if !ok || userID == "" {
+			c.Error(apierror.Unauthorized("Invalid token claims"))
+			c.Abort()
+			return
+		}
+
+		c.Set("userId", userID)
+		c.Next()
+	}
+}
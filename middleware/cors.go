@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig drives which origins, headers, and credentials policy the CORS
+// middleware advertises.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+}
+
+// CORSConfigFromEnv builds a CORSConfig from CORS_ALLOWED_ORIGINS (a
+// comma-separated list, with "*" as a wildcard) and CORS_ALLOW_CREDENTIALS.
+func CORSConfigFromEnv() CORSConfig {
+	origins := []string{"*"}
+	if raw := os.Getenv("CORS_ALLOWED_ORIGINS"); raw != "" {
+		origins = strings.Split(raw, ",")
+		for i := range origins {
+			origins[i] = strings.TrimSpace(origins[i])
+		}
+	}
+
+	allowCredentials := os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+	if allowCredentials && containsWildcard(origins) {
+		log.Println("CORS_ALLOW_CREDENTIALS=true requires an explicit CORS_ALLOWED_ORIGINS list (no \"*\"); ignoring credentials flag")
+		allowCredentials = false
+	}
+
+	return CORSConfig{
+		AllowedOrigins:   origins,
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowCredentials: allowCredentials,
+	}
+}
+
+func containsWildcard(origins []string) bool {
+	for _, o := range origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsOrigin reports whether origin is allowed, and whether that
+// permission came from an explicit match rather than the "*" wildcard.
+func (cfg CORSConfig) allowsOrigin(origin string) (allowed bool, explicit bool) {
+	for _, a := range cfg.AllowedOrigins {
+		if a == origin {
+			return true, true
+		}
+		if a == "*" {
+			allowed = true
+		}
+	}
+	return allowed, false
+}
+
+// CORS sets the Access-Control-* response headers per cfg and short-circuits
+// OPTIONS preflight requests with a 204.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin != "" {
+			if allowed, explicit := cfg.allowsOrigin(origin); allowed {
+				c.Header("Access-Control-Allow-Origin", origin)
+				c.Header("Vary", "Origin")
+				// Never reflect credentials onto a request that was only
+				// allowed via the "*" wildcard, even if AllowCredentials is
+				// set — that combination lets any site make credentialed
+				// requests, which defeats the point of opting into credentials.
+				if cfg.AllowCredentials && explicit {
+					c.Header("Access-Control-Allow-Credentials", "true")
+				}
+			}
+		}
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
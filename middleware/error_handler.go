@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/AbbasRizvi3/GoLangAssignment2/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler inspects c.Errors once the rest of the chain has run and
+// renders any error a handler reported via c.Error(...) as a single,
+// consistently shaped JSON body.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		apiErr, ok := err.(*apierror.APIError)
+		if !ok {
+			apiErr = apierror.Internal(err)
+		}
+
+		status := apiErr.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+
+		c.JSON(status, gin.H{"error": apiErr})
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/AbbasRizvi3/GoLangAssignment2/apierror"
+	"github.com/AbbasRizvi3/GoLangAssignment2/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const usersCollectionName = "users"
+
+type User struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Email    string             `json:"email" bson:"email"`
+	Password string             `json:"-" bson:"password"`
+}
+
+type registerRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func jwtExpiry() time.Duration {
+	hours := os.Getenv("JWT_EXPIRY_HOURS")
+	if hours == "" {
+		return 24 * time.Hour
+	}
+	n, err := strconv.Atoi(hours)
+	if err != nil || n <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(n) * time.Hour
+}
+
+func generateToken(userID primitive.ObjectID) (string, error) {
+	claims := jwt.MapClaims{
+		"userId": userID.Hex(),
+		"exp":    time.Now().Add(jwtExpiry()).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(middleware.JWTSecret())
+}
+
+func RegisterUser(c *gin.Context) {
+	var req registerRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.Error(apierror.BadRequest("invalid json"))
+		return
+	}
+
+	if req.Email == "" || req.Password == "" {
+		c.Error(apierror.Validation("Email and password are required", nil))
+		return
+	}
+	if len(req.Password) < 8 {
+		c.Error(apierror.Validation("Password length must be at least 8", nil))
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	user := User{Email: req.Email, Password: string(hashed)}
+
+	res, err := Client.Database(databaseName).Collection(usersCollectionName).InsertOne(context.Background(), user)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.Error(apierror.Conflict("Email already registered"))
+			return
+		}
+		c.Error(apierror.Internal(err))
+		return
+	}
+	user.ID = res.InsertedID.(primitive.ObjectID)
+
+	token, err := generateToken(user.ID)
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"message": "User registered",
+		"user":    user,
+		"token":   token,
+	})
+}
+
+func LoginUser(c *gin.Context) {
+	var req loginRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.Error(apierror.BadRequest("invalid json"))
+		return
+	}
+
+	var user User
+	err := Client.Database(databaseName).Collection(usersCollectionName).FindOne(context.Background(), bson.M{"email": req.Email}).Decode(&user)
+	if err != nil {
+		c.Error(apierror.Unauthorized("Invalid email or password"))
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		c.Error(apierror.Unauthorized("Invalid email or password"))
+		return
+	}
+
+	token, err := generateToken(user.ID)
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Login successful",
+		"token":   token,
+	})
+}